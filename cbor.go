@@ -24,25 +24,59 @@ type CBORProcessor struct {
 	encMode  cbor.EncMode
 	decMode  cbor.DecMode
 	operator func(msg *service.Message) error
+	tags     *tagRegistry
+
+	diagMode     cbor.DiagMode
+	diagSequence bool
+
+	schemaType reflect.Type
+
+	cose COSEConfig
+
+	tagDenyList map[uint64]bool
 }
 
-func NewProcessor(operatorStr string) (*CBORProcessor, error) {
+// Option configures optional behaviour of a CBORProcessor at
+// construction time.
+type Option func(*CBORProcessor) error
+
+// WithTags enables the tag registry, round-tripping the given tag
+// numbers through to_json/from_json using their configured JSON
+// representation instead of discarding the tag information.
+func WithTags(entries []TagEntry) Option {
+	return func(p *CBORProcessor) error {
+		reg, err := newTagRegistry(entries)
+		if err != nil {
+			return err
+		}
+		p.tags = reg
+		return nil
+	}
+}
+
+func NewProcessor(operatorStr string, opts ...Option) (*CBORProcessor, error) {
 	p := &CBORProcessor{}
 	operator, err := strToOperator(p, operatorStr)
 	if err != nil {
 		return nil, err
 	}
 
-	// Configure encoder options for JSON compatibility
-	encOpts := cbor.EncOptions{
-		ByteSliceLaterFormat: cbor.ByteSliceLaterFormatBase64,
-		String:               cbor.StringToByteString,
-		ByteArray:            cbor.ByteArrayToArray,
+	for _, opt := range opts {
+		if err := opt(p); err != nil {
+			return nil, err
+		}
 	}
 
-	// Create encoder mode
-	if p.encMode, err = encOpts.EncMode(); err != nil {
-		return nil, fmt.Errorf("failed to create CBOR encoder: %w", err)
+	if p.encMode == nil {
+		// Configure encoder options for JSON compatibility
+		encOpts, err := encodingPresetOptions(encodingDefault)
+		if err != nil {
+			return nil, err
+		}
+
+		if p.encMode, err = encOpts.EncMode(); err != nil {
+			return nil, fmt.Errorf("failed to create CBOR encoder: %w", err)
+		}
 	}
 
 	// Configure decoder options for JSON compatibility
@@ -58,6 +92,14 @@ func NewProcessor(operatorStr string) (*CBORProcessor, error) {
 		return nil, fmt.Errorf("failed to create CBOR decoder: %w", err)
 	}
 
+	if p.diagMode == nil {
+		diagMode, err := cbor.DiagOptions{ByteStringEncoding: cbor.ByteStringBase64Encoding}.DiagMode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CBOR diagnostic mode: %w", err)
+		}
+		p.diagMode = diagMode
+	}
+
 	p.operator = operator
 	return p, nil
 }
@@ -82,12 +124,35 @@ func newCBORToJSONOperator(cp *CBORProcessor) func(msg *service.Message) error {
 			return fmt.Errorf("failed to get message bytes: %w", err)
 		}
 
+		if cp.schemaType != nil {
+			instance := reflect.New(cp.schemaType).Interface()
+			if err := cp.decMode.Unmarshal(bytesContent, instance); err != nil {
+				return fmt.Errorf("failed to decode CBOR against schema: %w", err)
+			}
+
+			jsonData, err := json.Marshal(instance)
+			if err != nil {
+				return fmt.Errorf("failed to convert CBOR to JSON: %w", err)
+			}
+
+			msg.SetBytes(jsonData)
+			return nil
+		}
+
 		// Decode CBOR to a generic interface
 		var decoded any
 		if err := cp.decMode.Unmarshal(bytesContent, &decoded); err != nil {
 			return fmt.Errorf("failed to decode CBOR: %w %s", err, string(bytesContent))
 		}
 
+		if cp.tags != nil {
+			converted, err := cp.tags.walkDecoded(decoded)
+			if err != nil {
+				return fmt.Errorf("failed to translate CBOR tags: %w", err)
+			}
+			decoded = converted
+		}
+
 		// Convert to JSON
 		jsonData, err := json.Marshal(decoded)
 		if err != nil {
@@ -107,12 +172,39 @@ func newCBORFromJSONOperator(cp *CBORProcessor) func(msg *service.Message) error
 			return fmt.Errorf("failed to get message bytes: %w", err)
 		}
 
+		if cp.schemaType != nil {
+			instance := reflect.New(cp.schemaType).Interface()
+			if err := json.Unmarshal(bytesContent, instance); err != nil {
+				return fmt.Errorf("failed to parse JSON against schema: %w", err)
+			}
+
+			cborData, err := schemaEncMode.Marshal(instance)
+			if err != nil {
+				return fmt.Errorf("failed to encode JSON to CBOR: %w", err)
+			}
+
+			msg.SetBytes(cborData)
+			return nil
+		}
+
 		// Parse JSON
 		var jsonData any
 		if err := json.Unmarshal(bytesContent, &jsonData); err != nil {
 			return fmt.Errorf("failed to parse JSON: %w", err)
 		}
 
+		if cp.tags != nil {
+			converted, err := cp.tags.walkParsed(jsonData)
+			if err != nil {
+				return fmt.Errorf("failed to translate CBOR tag sentinels: %w", err)
+			}
+			jsonData = converted
+		}
+
+		if err := checkTagDenyList(jsonData, cp.tagDenyList); err != nil {
+			return err
+		}
+
 		// Encode to CBOR
 		cborData, err := cp.encMode.Marshal(jsonData)
 		if err != nil {
@@ -150,12 +242,81 @@ Converts CBOR data into JSON format.
 ### `+"`from_json`"+`
 
 Converts JSON data into CBOR format using the configured encoding options.
+
+### `+"`diagnose`"+`
+
+Decodes CBOR input and emits its Extended Diagnostic Notation (EDN, RFC 8610
+Appendix G) representation, a human-readable text form useful for debugging
+CBOR streams. Use `+"`cbor_sequence`"+` when the input is a CBOR Sequence (RFC
+8742) to emit one EDN document per top-level item rather than treating the
+whole payload as one.
+
+### `+"`cose_sign1_sign`"+` / `+"`cose_sign1_verify`"+` / `+"`cose_mac0_sign`"+` / `+"`cose_mac0_verify`"+`
+
+Wrap the message payload in a COSE_Sign1 or COSE_Mac0 structure (RFC 8152),
+or verify one and unwrap it back to the bare payload, setting the
+`+"`cose_verified`"+` metadata field to `+"`true`"+` on success. Configure `+"`algorithm`"+`,
+`+"`key`"+`, and optionally `+"`kid`"+`, `+"`protected_headers`"+` and `+"`external_aad`"+`.
+
+## Encoding
+
+The `+"`encoding`"+` field selects one of the fxamacker/cbor/v2 encoding-mode
+presets used by `+"`from_json`"+` when no `+"`schema`"+` is set. The COSE operators and
+schema-driven `+"`from_json`"+` do not use it; they always encode with plain CBOR
+options. The presets are `+"`default`"+` (this
+processor's own JSON-friendly options), `+"`core_deterministic`"+`,
+`+"`ctap2_canonical`"+`, or `+"`preferred_unsorted`"+`. Individual options
+(`+"`sort`"+`, `+"`shortest_float`"+`, `+"`nan_convert`"+`, `+"`inf_convert`"+`, `+"`time`"+`,
+`+"`time_tag`"+`, `+"`indef_length`"+`) can be overridden on top of whichever
+preset is chosen, and `+"`tag_deny_list`"+` rejects specific tag numbers from
+the output. Deterministic encoding is required for WebAuthn/CTAP2 and COSE
+signing, where the exact CBOR bytes are hashed or signed.
+
+## Schema
+
+By default `+"`to_json`"+`/`+"`from_json`"+` round-trip through a generic `+"`any`"+`
+value, which is convenient but verbose on the wire. Setting `+"`schema`"+`
+declares a fixed set of fields instead; they are built into a Go struct at
+start-up and encoded/decoded using fxamacker/cbor/v2's `+"`toarray`"+`/`+"`keyasint`"+`
+semantics, producing the compact CBOR layouts expected by CWT/COSE and IoT
+payloads.
+
+## Tags
+
+CBOR tags (RFC 8949 §3.4) attach a number to a value to signal its
+semantic type, e.g. a date/time string or a bignum. With an empty `+"`tags`"+`
+list, `+"`to_json`"+` still emits each tagged value as
+`+"`{\"@tag\": <n>, \"@value\": ...}`"+`, but `+"`from_json`"+` treats such objects as
+plain maps. Add entries to the `+"`tags`"+` field to round-trip them: on
+`+"`to_json`"+` a tagged value is emitted as `+"`{\"@tag\": <n>, \"@value\": ...}`"+` (or
+`+"`{\"<name>\": ...}`"+` when `+"`json_representation: named`"+`), and on
+`+"`from_json`"+` that same shape is recognised and re-encoded as a CBOR tag.
 `).
 		Fields(
-			service.NewStringEnumField(fieldOperator, "to_json", "from_json").
-				Description("The operator to execute, to_json|from_json").
+			service.NewStringEnumField(fieldOperator, "to_json", "from_json", operatorDiagnose,
+				operatorCoseSign1Sign, operatorCoseSign1Verify, operatorCoseMac0Sign, operatorCoseMac0Verify).
+				Description("The operator to execute, to_json|from_json|diagnose|cose_sign1_sign|cose_sign1_verify|cose_mac0_sign|cose_mac0_verify").
 				Default("to_json"),
+			service.NewObjectListField(fieldTags,
+				service.NewIntField(fieldTagNumber).
+					Description("The CBOR tag number this entry applies to."),
+				service.NewStringField(fieldTagName).
+					Description("A short name for the tag, used as the JSON key when json_representation is `named`.").
+					Default(""),
+				service.NewStringField(fieldTagGoType).
+					Description("Informational hint for the Go type this tag carries (e.g. `time.Time`, `bignum`). Not required for round-tripping.").
+					Default(""),
+				service.NewStringEnumField(fieldTagJSONRepresentation, string(representationWrapped), string(representationNamed)).
+					Description("How a tagged value is shaped in JSON: `wrapped` emits `{\"@tag\": <n>, \"@value\": ...}`, `named` emits `{\"<name>\": ...}`.").
+					Default(string(representationWrapped)),
+			).
+				Description("A registry of CBOR tag numbers to round-trip through to_json/from_json instead of dropping their type information.").
+				Default([]any{}),
 		).
+		Fields(diagnoseConfigFields()...).
+		Fields(schemaConfigField()).
+		Fields(coseConfigFields()...).
+		Fields(encodingConfigFields()...).
 		Example("Convert CBOR to JSON", `
 This example demonstrates how to convert CBOR data to JSON format.
 `, `
@@ -185,7 +346,34 @@ func init() {
 				return nil, err
 			}
 
-			return NewProcessor(operatorStr)
+			tagEntries, err := parseTagEntries(conf)
+			if err != nil {
+				return nil, err
+			}
+
+			diagnoseConfig, err := parseDiagnoseConfig(conf)
+			if err != nil {
+				return nil, err
+			}
+
+			schemaConfig, err := parseSchemaConfig(conf)
+			if err != nil {
+				return nil, err
+			}
+
+			coseConfig, err := parseCOSEConfig(conf)
+			if err != nil {
+				return nil, err
+			}
+
+			encodingConfig, err := parseEncodingConfig(conf)
+			if err != nil {
+				return nil, err
+			}
+
+			return NewProcessor(operatorStr,
+				WithTags(tagEntries), WithDiagnose(diagnoseConfig), WithSchema(schemaConfig), WithCOSE(coseConfig),
+				WithEncoding(encodingConfig))
 		})
 	if err != nil {
 		panic(err)
@@ -198,6 +386,16 @@ func strToOperator(p *CBORProcessor, operatorStr string) (func(msg *service.Mess
 		return newCBORToJSONOperator(p), nil
 	case "from_json":
 		return newCBORFromJSONOperator(p), nil
+	case operatorDiagnose:
+		return newCBORDiagnoseOperator(p), nil
+	case operatorCoseSign1Sign:
+		return newCoseSign1SignOperator(p), nil
+	case operatorCoseSign1Verify:
+		return newCoseSign1VerifyOperator(p), nil
+	case operatorCoseMac0Sign:
+		return newCoseMac0SignOperator(p), nil
+	case operatorCoseMac0Verify:
+		return newCoseMac0VerifyOperator(p), nil
 	default:
 		return nil, errors.New("invalid operator type")
 	}
@@ -4,6 +4,7 @@ import (
 	"context"
 	b64 "encoding/base64"
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/fxamacker/cbor/v2"
@@ -29,6 +30,32 @@ func mustEncodeMapToCBORBase64(t *testing.T, input map[string]any) string {
 	return b64.StdEncoding.EncodeToString(cborData)
 }
 
+// convertToStringKeyMap recursively converts the map[interface{}]interface{}
+// values produced by plain cbor.Unmarshal into map[string]any, and byte
+// strings (from_json's default preset encodes Go strings as CBOR byte
+// strings) into strings, so the result can be compared against a
+// JSON-derived expectation with assert.Equal.
+func convertToStringKeyMap(v any) any {
+	switch val := v.(type) {
+	case []byte:
+		return string(val)
+	case map[any]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			out[fmt.Sprint(k)] = convertToStringKeyMap(item)
+		}
+		return out
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			out[i] = convertToStringKeyMap(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
 func TestCBORToJson(t *testing.T) {
 	type testCase struct {
 		name           string
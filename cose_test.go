@@ -0,0 +1,69 @@
+package cbor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+func TestCOSEMac0SignAndVerify(t *testing.T) {
+	key := []byte("a very secret HMAC key used for testing only")
+
+	signer, err := NewProcessor(operatorCoseMac0Sign, WithCOSE(COSEConfig{
+		Algorithm: "HS256",
+		Key:       key,
+	}))
+	require.NoError(t, err)
+
+	msgs, err := signer.Process(context.Background(), service.NewMessage([]byte(`{"hello":"world"}`)))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	signed, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	verifier, err := NewProcessor(operatorCoseMac0Verify, WithCOSE(COSEConfig{
+		Algorithm: "HS256",
+		Key:       key,
+	}))
+	require.NoError(t, err)
+
+	msgs, err = verifier.Process(context.Background(), service.NewMessage(signed))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	verified, exists := msgs[0].MetaGetMut(metaCoseVerified)
+	require.True(t, exists)
+	require.Equal(t, true, verified)
+
+	payload, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	require.JSONEq(t, `{"hello":"world"}`, string(payload))
+}
+
+func TestCOSEMac0VerifyWrongKeyFails(t *testing.T) {
+	key := []byte("a very secret HMAC key used for testing only")
+
+	signer, err := NewProcessor(operatorCoseMac0Sign, WithCOSE(COSEConfig{
+		Algorithm: "HS256",
+		Key:       key,
+	}))
+	require.NoError(t, err)
+
+	msgs, err := signer.Process(context.Background(), service.NewMessage([]byte(`{"hello":"world"}`)))
+	require.NoError(t, err)
+
+	signed, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	verifier, err := NewProcessor(operatorCoseMac0Verify, WithCOSE(COSEConfig{
+		Algorithm: "HS256",
+		Key:       []byte("the wrong key entirely"),
+	}))
+	require.NoError(t, err)
+
+	_, err = verifier.Process(context.Background(), service.NewMessage(signed))
+	require.Error(t, err)
+}
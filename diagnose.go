@@ -0,0 +1,133 @@
+package cbor
+
+import (
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+const (
+	operatorDiagnose = "diagnose"
+
+	fieldDiagnoseSequence                = "cbor_sequence"
+	fieldDiagnoseByteStringEncoding      = "byte_string_encoding"
+	fieldDiagnoseFloatPrecisionIndicator = "float_precision_indicator"
+)
+
+var byteStringEncodings = map[string]cbor.ByteStringEncoding{
+	"base16": cbor.ByteStringBase16Encoding,
+	"base32": cbor.ByteStringBase32Encoding,
+	// ByteStringBase64Encoding is already base64url per its own doc comment.
+	"base64": cbor.ByteStringBase64Encoding,
+}
+
+// DiagnoseConfig controls how the diagnose operator renders CBOR as
+// Extended Diagnostic Notation (EDN, RFC 8610 Appendix G).
+type DiagnoseConfig struct {
+	Sequence                bool
+	ByteStringEncoding      string
+	FloatPrecisionIndicator bool
+}
+
+// WithDiagnose configures the diagnose operator's EDN rendering options.
+func WithDiagnose(cfg DiagnoseConfig) Option {
+	return func(p *CBORProcessor) error {
+		encoding, ok := byteStringEncodings[cfg.ByteStringEncoding]
+		if cfg.ByteStringEncoding == "" {
+			encoding = cbor.ByteStringBase64Encoding
+		} else if !ok {
+			return fmt.Errorf("invalid byte_string_encoding %q", cfg.ByteStringEncoding)
+		}
+
+		diagOpts := cbor.DiagOptions{
+			ByteStringEncoding:      encoding,
+			FloatPrecisionIndicator: cfg.FloatPrecisionIndicator,
+		}
+
+		diagMode, err := diagOpts.DiagMode()
+		if err != nil {
+			return fmt.Errorf("failed to create CBOR diagnostic mode: %w", err)
+		}
+
+		p.diagMode = diagMode
+		p.diagSequence = cfg.Sequence
+		return nil
+	}
+}
+
+func newCBORDiagnoseOperator(cp *CBORProcessor) func(msg *service.Message) error {
+	return func(msg *service.Message) error {
+		bytesContent, err := msg.AsBytes()
+		if err != nil {
+			return fmt.Errorf("failed to get message bytes: %w", err)
+		}
+
+		if !cp.diagSequence {
+			edn, err := cp.diagMode.Diagnose(bytesContent)
+			if err != nil {
+				return fmt.Errorf("failed to diagnose CBOR: %w", err)
+			}
+			msg.SetBytes([]byte(edn))
+			return nil
+		}
+
+		var docs [][]byte
+		rest := bytesContent
+		for len(rest) > 0 {
+			edn, remaining, err := cp.diagMode.DiagnoseFirst(rest)
+			if err != nil {
+				return fmt.Errorf("failed to diagnose CBOR sequence: %w", err)
+			}
+			docs = append(docs, []byte(edn))
+			rest = remaining
+		}
+
+		out := make([]byte, 0, len(bytesContent))
+		for i, doc := range docs {
+			if i > 0 {
+				out = append(out, '\n')
+			}
+			out = append(out, doc...)
+		}
+		msg.SetBytes(out)
+		return nil
+	}
+}
+
+// diagnoseConfigFields returns the sub-fields exposed for the diagnose
+// operator.
+func diagnoseConfigFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewBoolField(fieldDiagnoseSequence).
+			Description("When the input is a CBOR Sequence (RFC 8742), loop over the batch emitting one EDN document per top-level CBOR item instead of treating the whole payload as a single item.").
+			Default(false),
+		service.NewStringEnumField(fieldDiagnoseByteStringEncoding, "base16", "base32", "base64").
+			Description("The encoding used to render byte strings in the EDN output.").
+			Default("base64"),
+		service.NewBoolField(fieldDiagnoseFloatPrecisionIndicator).
+			Description("Whether to append a suffix indicating each float value's bit width (e.g. `_2` for float32) to the EDN output.").
+			Default(false),
+	}
+}
+
+func parseDiagnoseConfig(conf *service.ParsedConfig) (DiagnoseConfig, error) {
+	sequence, err := conf.FieldBool(fieldDiagnoseSequence)
+	if err != nil {
+		return DiagnoseConfig{}, err
+	}
+	encoding, err := conf.FieldString(fieldDiagnoseByteStringEncoding)
+	if err != nil {
+		return DiagnoseConfig{}, err
+	}
+	precisionIndicator, err := conf.FieldBool(fieldDiagnoseFloatPrecisionIndicator)
+	if err != nil {
+		return DiagnoseConfig{}, err
+	}
+
+	return DiagnoseConfig{
+		Sequence:                sequence,
+		ByteStringEncoding:      encoding,
+		FloatPrecisionIndicator: precisionIndicator,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package cbor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+func TestCBORDiagnose(t *testing.T) {
+	encMode, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	cborData, err := encMode.Marshal(map[string]any{"key": "foo"})
+	require.NoError(t, err)
+
+	proc, err := NewProcessor(operatorDiagnose)
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage(cborData))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	edn, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	require.Contains(t, string(edn), `"key"`)
+	require.Contains(t, string(edn), `"foo"`)
+}
+
+func TestCBORDiagnoseSequence(t *testing.T) {
+	encMode, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	first, err := encMode.Marshal(1)
+	require.NoError(t, err)
+	second, err := encMode.Marshal("two")
+	require.NoError(t, err)
+
+	proc, err := NewProcessor(operatorDiagnose, WithDiagnose(DiagnoseConfig{Sequence: true}))
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage(append(first, second...)))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	edn, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+	require.Equal(t, "1\n\"two\"", string(edn))
+}
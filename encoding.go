@@ -0,0 +1,312 @@
+package cbor
+
+import (
+	"fmt"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+const (
+	fieldEncoding = "encoding"
+
+	fieldEncodingSort          = "sort"
+	fieldEncodingShortestFloat = "shortest_float"
+	fieldEncodingNaNConvert    = "nan_convert"
+	fieldEncodingInfConvert    = "inf_convert"
+	fieldEncodingTime          = "time"
+	fieldEncodingTimeTag       = "time_tag"
+	fieldEncodingIndefLength   = "indef_length"
+	fieldEncodingTagDenyList   = "tag_deny_list"
+
+	encodingDefault           = "default"
+	encodingCoreDeterministic = "core_deterministic"
+	encodingCTAP2Canonical    = "ctap2_canonical"
+	encodingPreferredUnsorted = "preferred_unsorted"
+)
+
+// EncodingConfig selects between the fxamacker/cbor/v2 encoding-mode
+// presets and optionally overrides individual options on top of
+// whichever preset is chosen.
+type EncodingConfig struct {
+	Preset        string
+	Sort          string
+	ShortestFloat string
+	NaNConvert    string
+	InfConvert    string
+	Time          string
+	TimeTag       string
+	IndefLength   string
+	TagDenyList   []uint64
+}
+
+// WithEncoding selects the encoder options used by from_json when no
+// schema is set. The COSE operators and schema-driven from_json do not
+// use them; they encode with plain CBOR options (coseEncMode and
+// schemaEncMode). Deterministic presets are required for
+// WebAuthn/CTAP2 and COSE signing, where the exact CBOR bytes are
+// hashed or signed.
+func WithEncoding(cfg EncodingConfig) Option {
+	return func(p *CBORProcessor) error {
+		encOpts, err := encodingPresetOptions(cfg.Preset)
+		if err != nil {
+			return err
+		}
+
+		if err := applyEncodingOverrides(&encOpts, cfg); err != nil {
+			return err
+		}
+
+		encMode, err := encOpts.EncMode()
+		if err != nil {
+			return fmt.Errorf("failed to create CBOR encoder: %w", err)
+		}
+
+		p.encMode = encMode
+
+		if len(cfg.TagDenyList) > 0 {
+			deny := make(map[uint64]bool, len(cfg.TagDenyList))
+			for _, n := range cfg.TagDenyList {
+				deny[n] = true
+			}
+			p.tagDenyList = deny
+		}
+
+		return nil
+	}
+}
+
+func encodingPresetOptions(preset string) (cbor.EncOptions, error) {
+	switch preset {
+	case "", encodingDefault:
+		return cbor.EncOptions{
+			ByteSliceLaterFormat: cbor.ByteSliceLaterFormatBase64,
+			String:               cbor.StringToByteString,
+			ByteArray:            cbor.ByteArrayToArray,
+		}, nil
+	case encodingCoreDeterministic:
+		return cbor.CoreDetEncOptions(), nil
+	case encodingCTAP2Canonical:
+		return cbor.CTAP2EncOptions(), nil
+	case encodingPreferredUnsorted:
+		return cbor.PreferredUnsortedEncOptions(), nil
+	default:
+		return cbor.EncOptions{}, fmt.Errorf("unknown encoding preset %q", preset)
+	}
+}
+
+func applyEncodingOverrides(opts *cbor.EncOptions, cfg EncodingConfig) error {
+	if cfg.Sort != "" {
+		mode, ok := map[string]cbor.SortMode{
+			"none":               cbor.SortNone,
+			"length_first":       cbor.SortLengthFirst,
+			"bytewise_lexical":   cbor.SortBytewiseLexical,
+			"canonical":          cbor.SortCanonical,
+			"ctap2":              cbor.SortCTAP2,
+			"core_deterministic": cbor.SortCoreDeterministic,
+		}[cfg.Sort]
+		if !ok {
+			return fmt.Errorf("unknown sort %q", cfg.Sort)
+		}
+		opts.Sort = mode
+	}
+
+	if cfg.ShortestFloat != "" {
+		mode, ok := map[string]cbor.ShortestFloatMode{
+			"none":    cbor.ShortestFloatNone,
+			"float16": cbor.ShortestFloat16,
+		}[cfg.ShortestFloat]
+		if !ok {
+			return fmt.Errorf("unknown shortest_float %q", cfg.ShortestFloat)
+		}
+		opts.ShortestFloat = mode
+	}
+
+	if cfg.NaNConvert != "" {
+		mode, ok := map[string]cbor.NaNConvertMode{
+			"none":            cbor.NaNConvertNone,
+			"7e00":            cbor.NaNConvert7e00,
+			"preserve_signal": cbor.NaNConvertPreserveSignal,
+			"quiet":           cbor.NaNConvertQuiet,
+			"reject":          cbor.NaNConvertReject,
+		}[cfg.NaNConvert]
+		if !ok {
+			return fmt.Errorf("unknown nan_convert %q", cfg.NaNConvert)
+		}
+		opts.NaNConvert = mode
+	}
+
+	if cfg.InfConvert != "" {
+		mode, ok := map[string]cbor.InfConvertMode{
+			"none":    cbor.InfConvertNone,
+			"float16": cbor.InfConvertFloat16,
+			"reject":  cbor.InfConvertReject,
+		}[cfg.InfConvert]
+		if !ok {
+			return fmt.Errorf("unknown inf_convert %q", cfg.InfConvert)
+		}
+		opts.InfConvert = mode
+	}
+
+	if cfg.Time != "" {
+		mode, ok := map[string]cbor.TimeMode{
+			"unix":         cbor.TimeUnix,
+			"unix_micro":   cbor.TimeUnixMicro,
+			"unix_dynamic": cbor.TimeUnixDynamic,
+			"rfc3339":      cbor.TimeRFC3339,
+			"rfc3339_nano": cbor.TimeRFC3339Nano,
+		}[cfg.Time]
+		if !ok {
+			return fmt.Errorf("unknown time %q", cfg.Time)
+		}
+		opts.Time = mode
+	}
+
+	if cfg.TimeTag != "" {
+		mode, ok := map[string]cbor.EncTagMode{
+			"none":     cbor.EncTagNone,
+			"required": cbor.EncTagRequired,
+		}[cfg.TimeTag]
+		if !ok {
+			return fmt.Errorf("unknown time_tag %q", cfg.TimeTag)
+		}
+		opts.TimeTag = mode
+	}
+
+	if cfg.IndefLength != "" {
+		mode, ok := map[string]cbor.IndefLengthMode{
+			"allowed":   cbor.IndefLengthAllowed,
+			"forbidden": cbor.IndefLengthForbidden,
+		}[cfg.IndefLength]
+		if !ok {
+			return fmt.Errorf("unknown indef_length %q", cfg.IndefLength)
+		}
+		opts.IndefLength = mode
+	}
+
+	return nil
+}
+
+// checkTagDenyList walks a value about to be CBOR-encoded and returns
+// an error if it contains a cbor.Tag whose number is on deny. This lets
+// deterministic-encoding users forbid specific tags (e.g. embedded CBOR
+// inside a COSE payload) rather than relying on convention alone.
+func checkTagDenyList(v any, deny map[uint64]bool) error {
+	if len(deny) == 0 {
+		return nil
+	}
+
+	switch val := v.(type) {
+	case cbor.Tag:
+		if deny[val.Number] {
+			return fmt.Errorf("tag %d is denied by tag_deny_list", val.Number)
+		}
+		return checkTagDenyList(val.Content, deny)
+	case map[string]any:
+		for _, item := range val {
+			if err := checkTagDenyList(item, deny); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for _, item := range val {
+			if err := checkTagDenyList(item, deny); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func encodingConfigFields() []*service.ConfigField {
+	return []*service.ConfigField{
+		service.NewStringEnumField(fieldEncoding, encodingDefault, encodingCoreDeterministic, encodingCTAP2Canonical, encodingPreferredUnsorted).
+			Description("Selects a deterministic/canonical fxamacker/cbor/v2 encoding preset for from_json without a schema. The COSE operators and schema-driven from_json always encode with plain CBOR options. Deterministic encoding is required for WebAuthn/CTAP2 and COSE signing, where the exact CBOR bytes are hashed or signed.").
+			Default(encodingDefault),
+		service.NewStringEnumField(fieldEncodingSort, "none", "length_first", "bytewise_lexical", "canonical", "ctap2", "core_deterministic").
+			Description("Overrides the preset's map/struct key sort order.").
+			Default(""),
+		service.NewStringEnumField(fieldEncodingShortestFloat, "none", "float16").
+			Description("Overrides the preset's shortest floating point width policy.").
+			Default(""),
+		service.NewStringEnumField(fieldEncodingNaNConvert, "none", "7e00", "preserve_signal", "quiet", "reject").
+			Description("Overrides the preset's NaN conversion policy.").
+			Default(""),
+		service.NewStringEnumField(fieldEncodingInfConvert, "none", "float16", "reject").
+			Description("Overrides the preset's +-Inf conversion policy.").
+			Default(""),
+		service.NewStringEnumField(fieldEncodingTime, "unix", "unix_micro", "unix_dynamic", "rfc3339", "rfc3339_nano").
+			Description("Overrides the preset's time.Time encoding.").
+			Default(""),
+		service.NewStringEnumField(fieldEncodingTimeTag, "none", "required").
+			Description("Overrides whether encoded times carry the date/time CBOR tag.").
+			Default(""),
+		service.NewStringEnumField(fieldEncodingIndefLength, "allowed", "forbidden").
+			Description("Overrides whether indefinite-length items may be produced.").
+			Default(""),
+		service.NewIntListField(fieldEncodingTagDenyList).
+			Description("CBOR tag numbers that must not appear in the encoded output; from_json fails if a tag sentinel resolves to one of these.").
+			Default([]any{}),
+	}
+}
+
+func parseEncodingConfig(conf *service.ParsedConfig) (EncodingConfig, error) {
+	preset, err := conf.FieldString(fieldEncoding)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	sort, err := conf.FieldString(fieldEncodingSort)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	shortestFloat, err := conf.FieldString(fieldEncodingShortestFloat)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	nanConvert, err := conf.FieldString(fieldEncodingNaNConvert)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	infConvert, err := conf.FieldString(fieldEncodingInfConvert)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	timeMode, err := conf.FieldString(fieldEncodingTime)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	timeTag, err := conf.FieldString(fieldEncodingTimeTag)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	indefLength, err := conf.FieldString(fieldEncodingIndefLength)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+	denyList, err := conf.FieldIntList(fieldEncodingTagDenyList)
+	if err != nil {
+		return EncodingConfig{}, err
+	}
+
+	denyNumbers := make([]uint64, len(denyList))
+	for i, n := range denyList {
+		number, err := toUint64(n)
+		if err != nil {
+			return EncodingConfig{}, fmt.Errorf("%s: %w", fieldEncodingTagDenyList, err)
+		}
+		denyNumbers[i] = number
+	}
+
+	return EncodingConfig{
+		Preset:        preset,
+		Sort:          sort,
+		ShortestFloat: shortestFloat,
+		NaNConvert:    nanConvert,
+		InfConvert:    infConvert,
+		Time:          timeMode,
+		TimeTag:       timeTag,
+		IndefLength:   indefLength,
+		TagDenyList:   denyNumbers,
+	}, nil
+}
@@ -0,0 +1,51 @@
+package cbor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+func TestCBOREncodingDeterministicSortsMapKeys(t *testing.T) {
+	proc, err := NewProcessor("from_json", WithEncoding(EncodingConfig{Preset: encodingCoreDeterministic}))
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"b": 1, "a": 2}`)))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	data, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	// In a deterministic encoding map keys are sorted, so the key "a"
+	// (0x61 0x61) must be written before "b" (0x61 0x62) regardless of
+	// input order. 0x61 alone is also the one-byte text string header.
+	aIdx := indexOfTextKey(data, 'a')
+	bIdx := indexOfTextKey(data, 'b')
+	require.Greater(t, aIdx, -1)
+	require.Greater(t, bIdx, -1)
+	require.Less(t, aIdx, bIdx)
+}
+
+func TestCBOREncodingTagDenyList(t *testing.T) {
+	proc, err := NewProcessor("from_json", WithEncoding(EncodingConfig{TagDenyList: []uint64{24}}), WithTags([]TagEntry{
+		{Number: 24},
+	}))
+	require.NoError(t, err)
+
+	_, err = proc.Process(context.Background(), service.NewMessage([]byte(`{"@tag": 24, "@value": "c3R1Zmb="}`)))
+	require.Error(t, err)
+}
+
+// indexOfTextKey returns the offset of the one-character CBOR text string
+// k (header 0x61 followed by k) in data, or -1.
+func indexOfTextKey(data []byte, k byte) int {
+	for i := 0; i+1 < len(data); i++ {
+		if data[i] == 0x61 && data[i+1] == k {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,127 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/warpstreamlabs/bento/public/bloblang"
+)
+
+func init() {
+	decodeSpec := bloblang.NewPluginSpec().
+		Description("Decodes a CBOR byte value into a structured Bloblang value, without requiring a dedicated `cbor` processor stage.").
+		Param(bloblang.NewStringParam("tag_mode").
+			Description("Whether CBOR tags are `allowed` or `forbidden` in the input.").
+			Default("allowed")).
+		Param(bloblang.NewBoolParam("indef_length").
+			Description("Whether indefinite-length items are permitted in the input.").
+			Default(true)).
+		Param(bloblang.NewStringParam("byte_string_type").
+			Description("The Go type byte strings are decoded into: `string` or `bytes`.").
+			Default("string"))
+
+	if err := bloblang.RegisterMethodV2("cbor_decode", decodeSpec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		decMode, err := decModeFromParams(args)
+		if err != nil {
+			return nil, err
+		}
+
+		return func(v any) (any, error) {
+			b, err := bloblangValueToBytes(v)
+			if err != nil {
+				return nil, err
+			}
+
+			var out any
+			if err := decMode.Unmarshal(b, &out); err != nil {
+				return nil, fmt.Errorf("failed to decode CBOR: %w", err)
+			}
+			return out, nil
+		}, nil
+	}); err != nil {
+		panic(err)
+	}
+
+	encodeSpec := bloblang.NewPluginSpec().
+		Description("Encodes a Bloblang value into raw CBOR bytes, without requiring a dedicated `cbor` processor stage.")
+
+	if err := bloblang.RegisterMethodV2("cbor_encode", encodeSpec, func(args *bloblang.ParsedParams) (bloblang.Method, error) {
+		encOpts, err := encodingPresetOptions(encodingDefault)
+		if err != nil {
+			return nil, err
+		}
+		encMode, err := encOpts.EncMode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CBOR encoder: %w", err)
+		}
+
+		return func(v any) (any, error) {
+			data, err := encMode.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("failed to encode CBOR: %w", err)
+			}
+			return data, nil
+		}, nil
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func decModeFromParams(args *bloblang.ParsedParams) (cbor.DecMode, error) {
+	tagMode, err := args.GetString("tag_mode")
+	if err != nil {
+		return nil, err
+	}
+	indefLength, err := args.GetBool("indef_length")
+	if err != nil {
+		return nil, err
+	}
+	byteStringType, err := args.GetString("byte_string_type")
+	if err != nil {
+		return nil, err
+	}
+
+	decOpts := cbor.DecOptions{
+		MapKeyByteString: cbor.MapKeyByteStringAllowed,
+		DefaultMapType:   reflect.TypeOf(map[string]any{}),
+	}
+
+	switch tagMode {
+	case "allowed":
+		decOpts.TagsMd = cbor.TagsAllowed
+	case "forbidden":
+		decOpts.TagsMd = cbor.TagsForbidden
+	default:
+		return nil, fmt.Errorf("invalid tag_mode %q", tagMode)
+	}
+
+	if indefLength {
+		decOpts.IndefLength = cbor.IndefLengthAllowed
+	} else {
+		decOpts.IndefLength = cbor.IndefLengthForbidden
+	}
+
+	switch byteStringType {
+	case "string":
+		decOpts.DefaultByteStringType = reflect.TypeOf("")
+		decOpts.ByteStringToString = cbor.ByteStringToStringAllowed
+	case "bytes":
+		decOpts.DefaultByteStringType = reflect.TypeOf([]byte(nil))
+	default:
+		return nil, fmt.Errorf("invalid byte_string_type %q", byteStringType)
+	}
+
+	return decOpts.DecMode()
+}
+
+func bloblangValueToBytes(v any) ([]byte, error) {
+	switch b := v.(type) {
+	case []byte:
+		return b, nil
+	case string:
+		return []byte(b), nil
+	default:
+		return nil, fmt.Errorf("expected bytes or a string, got %T", v)
+	}
+}
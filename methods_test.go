@@ -0,0 +1,17 @@
+package cbor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/warpstreamlabs/bento/public/bloblang"
+)
+
+func TestBloblangCBOREncodeDecode(t *testing.T) {
+	exec, err := bloblang.Parse(`root = {"key":"value"}.cbor_encode().cbor_decode()`)
+	require.NoError(t, err)
+
+	res, err := exec.Query(nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]any{"key": "value"}, res)
+}
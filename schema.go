@@ -0,0 +1,282 @@
+package cbor
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+// schemaEncMode encodes schema-typed values with plain CBOR semantics:
+// a Go string field is a CBOR text string, not a byte string. cp.encMode
+// is unsuitable here because its JSON-friendly default (encoding.go's
+// String: cbor.StringToByteString) would silently turn a `go_kind:
+// "string"` field into a byte string, breaking interop with the
+// standards-conformant CWT/COSE consumers this feature targets.
+var schemaEncMode = func() cbor.EncMode {
+	encMode, err := cbor.EncOptions{}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	return encMode
+}()
+
+const (
+	fieldSchema          = "schema"
+	fieldSchemaLayout    = "layout"
+	fieldSchemaFields    = "fields"
+	fieldSchemaFieldName = "name"
+	fieldSchemaFieldKey  = "key"
+	fieldSchemaFieldKind = "go_kind"
+	fieldSchemaRequired  = "required"
+
+	layoutMap      = "map"
+	layoutArray    = "array"
+	layoutKeyasint = "keyasint"
+
+	// schemaPkgPath is the PkgPath reflect.StructOf requires on the
+	// unexported "_" toarray marker field synthesized by buildSchemaType.
+	schemaPkgPath = "github.com/akhenakh/bento-cbor"
+)
+
+// SchemaField declares one field of a user-supplied CBOR schema.
+type SchemaField struct {
+	Name     string
+	Key      string
+	GoKind   string
+	Required bool
+}
+
+// SchemaConfig declares a typed CBOR schema, built into a Go struct type
+// at processor construction time so encoding/decoding can use
+// `fxamacker/cbor/v2`'s `toarray`/`keyasint` semantics for compact,
+// CWT/COSE-friendly output.
+type SchemaConfig struct {
+	Layout string
+	Fields []SchemaField
+}
+
+// WithSchema switches the processor's to_json/from_json operators to
+// encode/decode through a struct type generated from cfg instead of a
+// generic `any`.
+func WithSchema(cfg SchemaConfig) Option {
+	return func(p *CBORProcessor) error {
+		if len(cfg.Fields) == 0 {
+			return nil
+		}
+
+		schemaType, err := buildSchemaType(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to build schema: %w", err)
+		}
+
+		p.schemaType = schemaType
+		return nil
+	}
+}
+
+// buildSchemaType constructs a reflect.Type for cfg using
+// reflect.StructOf, tagging each field so that fxamacker/cbor/v2
+// encodes/decodes it per the chosen layout.
+func buildSchemaType(cfg SchemaConfig) (reflect.Type, error) {
+	layout := cfg.Layout
+	if layout == "" {
+		layout = layoutMap
+	}
+
+	var structFields []reflect.StructField
+
+	if layout == layoutArray {
+		// reflect.StructOf panics on an unexported field ("_") unless
+		// PkgPath is set, unlike a hand-written `_ struct{} `cbor:",toarray"``
+		// field in source. The field's data is never read; only its tag
+		// is, via reflect.Type, so this is otherwise equivalent.
+		structFields = append(structFields, reflect.StructField{
+			Name:    "_",
+			PkgPath: schemaPkgPath,
+			Type:    reflect.TypeOf(struct{}{}),
+			Tag:     `cbor:",toarray"`,
+		})
+	}
+
+	usedNames := make(map[string]bool, len(cfg.Fields))
+	for i, f := range cfg.Fields {
+		goType, err := schemaKindToType(f.GoKind)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", f.Name, err)
+		}
+
+		exportedName := exportSchemaFieldName(f.Name, i)
+		for usedNames[exportedName] {
+			exportedName = fmt.Sprintf("%s_%d", exportedName, i)
+		}
+		usedNames[exportedName] = true
+
+		var cborTag string
+		switch layout {
+		case layoutMap:
+			key := f.Key
+			if key == "" {
+				key = f.Name
+			}
+			cborTag = key
+		case layoutKeyasint:
+			key := f.Key
+			if key == "" {
+				return nil, fmt.Errorf("field %q: a numeric key is required for layout %q", f.Name, layoutKeyasint)
+			}
+			cborTag = key + ",keyasint"
+		case layoutArray:
+			// Field order determines array position; no per-field tag
+			// is needed.
+		default:
+			return nil, fmt.Errorf("unknown schema layout %q", layout)
+		}
+
+		if layout != layoutArray && !f.Required {
+			if cborTag != "" {
+				cborTag += ",omitempty"
+			} else {
+				cborTag = "omitempty"
+			}
+		}
+
+		tagParts := []string{fmt.Sprintf(`json:%q`, f.Name)}
+		if cborTag != "" {
+			tagParts = append([]string{fmt.Sprintf(`cbor:%q`, cborTag)}, tagParts...)
+		}
+
+		structFields = append(structFields, reflect.StructField{
+			Name: exportedName,
+			Type: goType,
+			Tag:  reflect.StructTag(strings.Join(tagParts, " ")),
+		})
+	}
+
+	return reflect.StructOf(structFields), nil
+}
+
+// exportSchemaFieldName turns a user-supplied field name into a valid
+// exported Go identifier, falling back to Field<index> if nothing of
+// the name survives.
+func exportSchemaFieldName(name string, index int) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r) || (r == '_' && b.Len() > 0):
+			if b.Len() == 0 {
+				r = unicode.ToUpper(r)
+			}
+			b.WriteRune(r)
+		case unicode.IsDigit(r) && b.Len() > 0:
+			b.WriteRune(r)
+		}
+	}
+
+	if b.Len() == 0 {
+		return fmt.Sprintf("Field%d", index)
+	}
+	s := b.String()
+	if first, _ := utf8.DecodeRuneInString(s); !unicode.IsUpper(first) {
+		// A letter with no upper-case form cannot start an exported name.
+		s = "X" + s
+	}
+	return s
+}
+
+func schemaKindToType(kind string) (reflect.Type, error) {
+	switch kind {
+	case "string":
+		return reflect.TypeOf(""), nil
+	case "int", "int64":
+		return reflect.TypeOf(int64(0)), nil
+	case "uint64":
+		return reflect.TypeOf(uint64(0)), nil
+	case "float64":
+		return reflect.TypeOf(float64(0)), nil
+	case "bool":
+		return reflect.TypeOf(false), nil
+	case "bytes":
+		return reflect.TypeOf([]byte(nil)), nil
+	case "any", "":
+		return reflect.TypeOf((*any)(nil)).Elem(), nil
+	default:
+		return nil, fmt.Errorf("unsupported go_kind %q", kind)
+	}
+}
+
+func schemaConfigField() *service.ConfigField {
+	return service.NewObjectField(fieldSchema,
+		service.NewStringEnumField(fieldSchemaLayout, layoutMap, layoutArray, layoutKeyasint).
+			Description("How schema fields are laid out in CBOR: `map` (string keys), `array` (`toarray`, position-based), or `keyasint` (integer keys), per fxamacker/cbor/v2 semantics.").
+			Default(layoutMap),
+		service.NewObjectListField(fieldSchemaFields,
+			service.NewStringField(fieldSchemaFieldName).
+				Description("The JSON field name."),
+			service.NewStringField(fieldSchemaFieldKey).
+				Description("The CBOR map key (layout `map`) or integer key (layout `keyasint`). Ignored for layout `array`, where field order determines position.").
+				Default(""),
+			service.NewStringEnumField(fieldSchemaFieldKind, "string", "int64", "uint64", "float64", "bool", "bytes", "any").
+				Description("The Go kind used to decode/encode this field's value.").
+				Default("any"),
+			service.NewBoolField(fieldSchemaRequired).
+				Description("Whether this field must always be present. Non-required fields are omitted from the CBOR output when empty (ignored for layout `array`).").
+				Default(false),
+		).
+			Description("The schema's fields.").
+			Default([]any{}),
+	).
+		Description("Declare a typed schema so CBOR is encoded/decoded through a generated struct instead of a generic value, producing compact output suitable for CWT/COSE and IoT payloads.").
+		Optional()
+}
+
+func parseSchemaConfig(conf *service.ParsedConfig) (SchemaConfig, error) {
+	if !conf.Contains(fieldSchema) {
+		return SchemaConfig{}, nil
+	}
+
+	schemaConf := conf.Namespace(fieldSchema)
+
+	layout, err := schemaConf.FieldString(fieldSchemaLayout)
+	if err != nil {
+		return SchemaConfig{}, err
+	}
+
+	fieldObjs, err := schemaConf.FieldObjectList(fieldSchemaFields)
+	if err != nil {
+		return SchemaConfig{}, err
+	}
+
+	fields := make([]SchemaField, 0, len(fieldObjs))
+	for _, obj := range fieldObjs {
+		name, err := obj.FieldString(fieldSchemaFieldName)
+		if err != nil {
+			return SchemaConfig{}, err
+		}
+		key, err := obj.FieldString(fieldSchemaFieldKey)
+		if err != nil {
+			return SchemaConfig{}, err
+		}
+		kind, err := obj.FieldString(fieldSchemaFieldKind)
+		if err != nil {
+			return SchemaConfig{}, err
+		}
+		required, err := obj.FieldBool(fieldSchemaRequired)
+		if err != nil {
+			return SchemaConfig{}, err
+		}
+
+		fields = append(fields, SchemaField{
+			Name:     name,
+			Key:      key,
+			GoKind:   kind,
+			Required: required,
+		})
+	}
+
+	return SchemaConfig{Layout: layout, Fields: fields}, nil
+}
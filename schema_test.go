@@ -0,0 +1,74 @@
+package cbor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+func TestCBORSchemaKeyasintRoundTrip(t *testing.T) {
+	cfg := SchemaConfig{
+		Layout: layoutKeyasint,
+		Fields: []SchemaField{
+			{Name: "subject", Key: "2", GoKind: "string", Required: true},
+			{Name: "issued_at", Key: "6", GoKind: "int64", Required: true},
+		},
+	}
+
+	fromJSON, err := NewProcessor("from_json", WithSchema(cfg))
+	require.NoError(t, err)
+
+	input := service.NewMessage([]byte(`{"subject": "alice", "issued_at": 1700000000}`))
+	msgs, err := fromJSON.Process(context.Background(), input)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	cborData, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	var asMap map[int]any
+	require.NoError(t, cbor.Unmarshal(cborData, &asMap))
+	require.Equal(t, "alice", asMap[2])
+
+	toJSON, err := NewProcessor("to_json", WithSchema(cfg))
+	require.NoError(t, err)
+
+	msgs, err = toJSON.Process(context.Background(), service.NewMessage(cborData))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	jsonBytes, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	var decoded map[string]any
+	require.NoError(t, json.Unmarshal(jsonBytes, &decoded))
+	require.Equal(t, "alice", decoded["subject"])
+}
+
+func TestCBORSchemaArrayLayout(t *testing.T) {
+	cfg := SchemaConfig{
+		Layout: layoutArray,
+		Fields: []SchemaField{
+			{Name: "lat", GoKind: "float64", Required: true},
+			{Name: "lon", GoKind: "float64", Required: true},
+		},
+	}
+
+	fromJSON, err := NewProcessor("from_json", WithSchema(cfg))
+	require.NoError(t, err)
+
+	msgs, err := fromJSON.Process(context.Background(), service.NewMessage([]byte(`{"lat": 1.5, "lon": -2.5}`)))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	cborData, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	var asArray []float64
+	require.NoError(t, cbor.Unmarshal(cborData, &asArray))
+	require.Equal(t, []float64{1.5, -2.5}, asArray)
+}
@@ -0,0 +1,162 @@
+package cbor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+const scannerFieldName = "cbor_sequence"
+
+// cborSequenceScanner splits an io.Reader containing a CBOR Sequence
+// (RFC 8742) — zero or more concatenated top-level CBOR data items with
+// no delimiter between them — into one Bento message per item.
+type cborSequenceScanner struct {
+	rdr    io.ReadCloser
+	dec    *cbor.Decoder
+	ackFn  service.AckFunc
+	mut    sync.Mutex
+	closed bool
+}
+
+func (s *cborSequenceScanner) NextBatch(ctx context.Context) (service.MessageBatch, service.AckFunc, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.closed {
+		return nil, nil, io.EOF
+	}
+
+	var raw cbor.RawMessage
+	if err := s.dec.Decode(&raw); err != nil {
+		if err == io.EOF {
+			return nil, nil, io.EOF
+		}
+		return nil, nil, fmt.Errorf("failed to decode CBOR sequence item: %w", err)
+	}
+
+	msg := service.NewMessage([]byte(raw))
+	return service.MessageBatch{msg}, s.ackFn, nil
+}
+
+func (s *cborSequenceScanner) Close(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.rdr.Close()
+}
+
+type cborSequenceScannerCreator struct{}
+
+func (cborSequenceScannerCreator) Create(rdr io.ReadCloser, ackFn service.AckFunc, details *service.ScannerSourceDetails) (service.BatchScanner, error) {
+	return &cborSequenceScanner{
+		rdr:   rdr,
+		dec:   cbor.NewDecoder(rdr),
+		ackFn: ackFn,
+	}, nil
+}
+
+func (cborSequenceScannerCreator) Close(ctx context.Context) error {
+	return nil
+}
+
+func init() {
+	spec := service.NewConfigSpec().
+		Stable().
+		Categories("Parsing").
+		Summary("Consumes a stream of concatenated CBOR data items (a CBOR Sequence, RFC 8742), emitting one message per top-level item.").
+		Description(`
+Unlike the `+"`cbor`"+` processor, which expects a single CBOR-encoded payload per
+message, this scanner splits an arbitrary byte stream on CBOR item
+boundaries with no delimiter required between items. This suits log
+files, WebAuthn attestation streams, or batches of CWTs where many CBOR
+values share one file or socket.
+`)
+
+	if err := service.RegisterBatchScannerCreator(scannerFieldName, spec,
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.BatchScannerCreator, error) {
+			return cborSequenceScannerCreator{}, nil
+		}); err != nil {
+		panic(err)
+	}
+}
+
+// cborSequenceOutput writes each message's raw bytes back-to-back with
+// no delimiter, producing a valid CBOR Sequence (RFC 8742) on disk.
+type cborSequenceOutput struct {
+	path string
+
+	mut sync.Mutex
+	f   *os.File
+}
+
+const fieldSequenceOutputPath = "path"
+
+func init() {
+	spec := service.NewConfigSpec().
+		Stable().
+		Categories("Parsing").
+		Summary("Writes each message as a raw CBOR data item, concatenated with no delimiter, producing a CBOR Sequence (RFC 8742).").
+		Fields(
+			service.NewStringField(fieldSequenceOutputPath).
+				Description("The file path to append CBOR Sequence items to."),
+		)
+
+	if err := service.RegisterOutput(scannerFieldName, spec,
+		func(conf *service.ParsedConfig, mgr *service.Resources) (service.Output, int, error) {
+			path, err := conf.FieldString(fieldSequenceOutputPath)
+			if err != nil {
+				return nil, 0, err
+			}
+			return &cborSequenceOutput{path: path}, 1, nil
+		}); err != nil {
+		panic(err)
+	}
+}
+
+func (o *cborSequenceOutput) Connect(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	f, err := os.OpenFile(o.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open %q: %w", o.path, err)
+	}
+	o.f = f
+	return nil
+}
+
+func (o *cborSequenceOutput) Write(ctx context.Context, msg *service.Message) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	data, err := msg.AsBytes()
+	if err != nil {
+		return fmt.Errorf("failed to get message bytes: %w", err)
+	}
+
+	if _, err := io.Copy(o.f, bytes.NewReader(data)); err != nil {
+		return fmt.Errorf("failed to write CBOR sequence item: %w", err)
+	}
+	return nil
+}
+
+func (o *cborSequenceOutput) Close(ctx context.Context) error {
+	o.mut.Lock()
+	defer o.mut.Unlock()
+
+	if o.f == nil {
+		return nil
+	}
+	return o.f.Close()
+}
@@ -0,0 +1,70 @@
+package cbor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+func TestCBORSequenceScanner(t *testing.T) {
+	encMode, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	first, err := encMode.Marshal(map[string]any{"a": 1})
+	require.NoError(t, err)
+	second, err := encMode.Marshal(map[string]any{"b": 2})
+	require.NoError(t, err)
+
+	rdr := io.NopCloser(bytes.NewReader(append(first, second...)))
+
+	scanner, err := (cborSequenceScannerCreator{}).Create(rdr, func(context.Context, error) error { return nil }, nil)
+	require.NoError(t, err)
+
+	batch, _, err := scanner.NextBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	firstBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	require.Equal(t, first, firstBytes)
+
+	batch, _, err = scanner.NextBatch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, batch, 1)
+	secondBytes, err := batch[0].AsBytes()
+	require.NoError(t, err)
+	require.Equal(t, second, secondBytes)
+
+	_, _, err = scanner.NextBatch(context.Background())
+	require.ErrorIs(t, err, io.EOF)
+
+	require.NoError(t, scanner.Close(context.Background()))
+}
+
+func TestCBORSequenceOutput(t *testing.T) {
+	encMode, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	first, err := encMode.Marshal(map[string]any{"a": 1})
+	require.NoError(t, err)
+	second, err := encMode.Marshal(map[string]any{"b": 2})
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "sequence.cbor")
+	out := &cborSequenceOutput{path: path}
+
+	require.NoError(t, out.Connect(context.Background()))
+	require.NoError(t, out.Write(context.Background(), service.NewMessage(first)))
+	require.NoError(t, out.Write(context.Background(), service.NewMessage(second)))
+	require.NoError(t, out.Close(context.Background()))
+
+	written, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, append(first, second...), written)
+}
@@ -0,0 +1,434 @@
+package cbor
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/big"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+const (
+	fieldTags                  = "tags"
+	fieldTagNumber             = "number"
+	fieldTagName               = "name"
+	fieldTagGoType             = "go_type"
+	fieldTagJSONRepresentation = "json_representation"
+)
+
+const (
+	// tagKey and tagValueKey are the default JSON sentinel keys used to
+	// wrap a tagged CBOR value, e.g. {"@tag": 1, "@value": 1609459200}.
+	tagKey      = "@tag"
+	tagValueKey = "@value"
+)
+
+// jsonRepresentation controls how a tagged value is shaped once it
+// reaches JSON.
+type jsonRepresentation string
+
+const (
+	// representationWrapped emits {"@tag": <number>, "@value": <value>}.
+	representationWrapped jsonRepresentation = "wrapped"
+	// representationNamed emits {"<name>": <value>} using the entry's
+	// configured name instead of the raw tag number.
+	representationNamed jsonRepresentation = "named"
+)
+
+// TagEntry describes one CBOR tag number known to a CBORProcessor,
+// including the JSON shape used to round-trip it through to_json and
+// from_json.
+type TagEntry struct {
+	Number             uint64
+	Name               string
+	GoType             string
+	JSONRepresentation string
+}
+
+// TagHandler converts a tag's content between its decoded Go
+// representation and the shape that is written to (or read from) JSON.
+// Handlers registered via RegisterTagHandler are consulted before a
+// tag's content is wrapped in the configured JSON representation,
+// allowing e.g. a byte-string bignum to be rendered as a JSON number.
+type TagHandler struct {
+	// DecodeContent converts a tag's decoded content into a JSON-safe
+	// value. It may return the content unchanged.
+	DecodeContent func(content any) (any, error)
+	// EncodeContent converts a JSON value back into the content that
+	// will be wrapped in a cbor.Tag before being CBOR-encoded.
+	EncodeContent func(value any) (any, error)
+}
+
+// tagHandlers holds the built-in and user-registered handlers, keyed by
+// tag number. It is populated by init() below and by RegisterTagHandler.
+var tagHandlers = map[uint64]TagHandler{}
+
+// RegisterTagHandler installs a custom content handler for the given
+// CBOR tag number, overriding any built-in handler. This is the
+// extension point for plugins that need to support additional tag
+// numbers beyond the built-ins registered by this package.
+func RegisterTagHandler(number uint64, handler TagHandler) {
+	tagHandlers[number] = handler
+}
+
+func init() {
+	// tag 0: standard date/time string (RFC 3339) and tag 1: epoch-based
+	// date/time. Both round-trip as-is; the content fxamacker/cbor
+	// decodes for these tags (string / number) is already JSON-safe.
+	RegisterTagHandler(0, TagHandler{})
+	RegisterTagHandler(1, TagHandler{})
+
+	// tag 2/3: bignum / negative bignum, carried as a CBOR byte string.
+	// Our decoder configuration turns byte strings into Go strings, so
+	// the raw big-endian bytes arrive here as a Go string that is not
+	// necessarily valid UTF-8; base64-encode it so it survives json.Marshal
+	// instead of being corrupted by invalid-UTF-8 replacement, and reverse
+	// that on the way back in.
+	RegisterTagHandler(2, TagHandler{
+		DecodeContent: bignumDecodeContent,
+		EncodeContent: bignumEncodeContent,
+	})
+	RegisterTagHandler(3, TagHandler{
+		DecodeContent: bignumDecodeContent,
+		EncodeContent: bignumEncodeContent,
+	})
+
+	// tag 24: CBOR data item encoded as a byte string (embedded CBOR).
+	RegisterTagHandler(24, TagHandler{})
+
+	// tag 30: rational number, a two-element array [numerator, denominator].
+	RegisterTagHandler(30, TagHandler{})
+
+	// tag 258: set, represented as a CBOR array with unique elements.
+	RegisterTagHandler(258, TagHandler{})
+
+	// tag 259: map with non-string keys, represented as an array of
+	// [key, value] pairs since JSON objects require string keys.
+	RegisterTagHandler(259, TagHandler{})
+}
+
+// bignumDecodeContent base64-encodes a bignum's raw big-endian byte
+// string so it survives JSON encoding unchanged.
+func bignumDecodeContent(content any) (any, error) {
+	raw, ok := content.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a byte string, got %T", content)
+	}
+	return base64.StdEncoding.EncodeToString([]byte(raw)), nil
+}
+
+// bignumToTag reverses fxamacker/cbor's built-in bignum decoding,
+// turning a decoded big.Int back into the tag number and raw
+// big-endian content bytes it came from.
+func bignumToTag(bi *big.Int) cbor.Tag {
+	if bi.Sign() < 0 {
+		raw := new(big.Int).Neg(bi)
+		raw.Sub(raw, big.NewInt(1))
+		return cbor.Tag{Number: 3, Content: string(raw.Bytes())}
+	}
+	return cbor.Tag{Number: 2, Content: string(bi.Bytes())}
+}
+
+// bignumEncodeContent reverses bignumDecodeContent, turning the
+// base64 JSON string back into the raw bytes that will be wrapped in
+// a CBOR byte string.
+func bignumEncodeContent(value any) (any, error) {
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("expected a base64 string, got %T", value)
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 bignum content: %w", err)
+	}
+	return string(raw), nil
+}
+
+// builtinTagNames gives default names for the tags this package ships
+// handlers for, used by the "named" JSON representation when a user
+// enables a built-in tag without overriding its name.
+var builtinTagNames = map[uint64]string{
+	0:   "datetime",
+	1:   "epoch_datetime",
+	2:   "bignum",
+	3:   "negative_bignum",
+	24:  "embedded_cbor",
+	30:  "rational",
+	258: "set",
+	259: "map_with_non_string_keys",
+}
+
+// parseTagEntries reads the `tags` array out of a parsed config.
+func parseTagEntries(conf *service.ParsedConfig) ([]TagEntry, error) {
+	objs, err := conf.FieldObjectList(fieldTags)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]TagEntry, 0, len(objs))
+	for _, obj := range objs {
+		number, err := obj.FieldInt(fieldTagNumber)
+		if err != nil {
+			return nil, err
+		}
+		name, err := obj.FieldString(fieldTagName)
+		if err != nil {
+			return nil, err
+		}
+		goType, err := obj.FieldString(fieldTagGoType)
+		if err != nil {
+			return nil, err
+		}
+		representation, err := obj.FieldString(fieldTagJSONRepresentation)
+		if err != nil {
+			return nil, err
+		}
+
+		entries = append(entries, TagEntry{
+			Number:             uint64(number),
+			Name:               name,
+			GoType:             goType,
+			JSONRepresentation: representation,
+		})
+	}
+	return entries, nil
+}
+
+// tagRegistry is a CBORProcessor's resolved view of its configured
+// tags: which numbers are active, what name and representation each
+// uses, and a reverse lookup from name back to number for the "named"
+// representation on encode.
+type tagRegistry struct {
+	byNumber map[uint64]TagEntry
+	byName   map[string]uint64
+}
+
+func newTagRegistry(entries []TagEntry) (*tagRegistry, error) {
+	reg := &tagRegistry{
+		byNumber: make(map[uint64]TagEntry, len(entries)),
+		byName:   make(map[string]uint64, len(entries)),
+	}
+
+	for _, entry := range entries {
+		if entry.JSONRepresentation == "" {
+			entry.JSONRepresentation = string(representationWrapped)
+		}
+		if entry.Name == "" {
+			entry.Name = builtinTagNames[entry.Number]
+		}
+		if entry.JSONRepresentation == string(representationNamed) && entry.Name == "" {
+			return nil, fmt.Errorf("tag %d: a name is required when json_representation is %q", entry.Number, representationNamed)
+		}
+
+		reg.byNumber[entry.Number] = entry
+		if entry.JSONRepresentation == string(representationNamed) {
+			reg.byName[entry.Name] = entry.Number
+		}
+	}
+
+	return reg, nil
+}
+
+// decodeTagValue converts a decoded CBOR tag into its JSON-safe shape
+// according to the entry's configured representation, applying any
+// registered TagHandler to the tag's content first.
+func (r *tagRegistry) decodeTagValue(tag cbor.Tag) (any, error) {
+	entry, ok := r.byNumber[tag.Number]
+	if !ok {
+		// Unconfigured tag numbers still round-trip via the default
+		// wrapped representation so no information is silently lost.
+		entry = TagEntry{Number: tag.Number, JSONRepresentation: string(representationWrapped)}
+	}
+
+	content := tag.Content
+	if handler, ok := tagHandlers[tag.Number]; ok && handler.DecodeContent != nil {
+		converted, err := handler.DecodeContent(content)
+		if err != nil {
+			return nil, fmt.Errorf("tag %d: decode content: %w", tag.Number, err)
+		}
+		content = converted
+	}
+
+	if jsonRepresentation(entry.JSONRepresentation) == representationNamed && entry.Name != "" {
+		return map[string]any{entry.Name: content}, nil
+	}
+
+	return map[string]any{
+		tagKey:      tag.Number,
+		tagValueKey: content,
+	}, nil
+}
+
+// encodeTagValue recognizes a tag sentinel produced by decodeTagValue
+// (or hand-written by a user) and turns it back into a cbor.Tag ready
+// for CBOR encoding. ok is false when m is not a tag sentinel.
+func (r *tagRegistry) encodeTagValue(m map[string]any) (value cbor.Tag, ok bool, err error) {
+	if raw, has := m[tagKey]; has && len(m) == 2 {
+		content, hasValue := m[tagValueKey]
+		if !hasValue {
+			return cbor.Tag{}, false, nil
+		}
+
+		number, err := toUint64(raw)
+		if err != nil {
+			return cbor.Tag{}, false, fmt.Errorf("%q: %w", tagKey, err)
+		}
+
+		if _, configured := r.byNumber[number]; !configured {
+			// Only treat {"@tag", "@value"}-shaped objects as a tag
+			// sentinel when the tag number is actually configured;
+			// otherwise an unrelated user object that happens to use
+			// these two literal keys (e.g. under the default empty
+			// tags: [] config) would be silently rewritten into a
+			// CBOR tag.
+			return cbor.Tag{}, false, nil
+		}
+
+		if handler, ok := tagHandlers[number]; ok && handler.EncodeContent != nil {
+			converted, err := handler.EncodeContent(content)
+			if err != nil {
+				return cbor.Tag{}, false, fmt.Errorf("tag %d: encode content: %w", number, err)
+			}
+			content = converted
+		}
+
+		return cbor.Tag{Number: number, Content: content}, true, nil
+	}
+
+	if len(m) == 1 {
+		for name, content := range m {
+			number, ok := r.byName[name]
+			if !ok {
+				return cbor.Tag{}, false, nil
+			}
+
+			if handler, ok := tagHandlers[number]; ok && handler.EncodeContent != nil {
+				converted, err := handler.EncodeContent(content)
+				if err != nil {
+					return cbor.Tag{}, false, fmt.Errorf("tag %d: encode content: %w", number, err)
+				}
+				content = converted
+			}
+
+			return cbor.Tag{Number: number, Content: content}, true, nil
+		}
+	}
+
+	return cbor.Tag{}, false, nil
+}
+
+func toUint64(v any) (uint64, error) {
+	switch n := v.(type) {
+	case uint64:
+		return n, nil
+	case int64:
+		if n < 0 {
+			return 0, fmt.Errorf("tag number must not be negative, got %d", n)
+		}
+		return uint64(n), nil
+	case float64:
+		if n < 0 {
+			return 0, fmt.Errorf("tag number must not be negative, got %v", n)
+		}
+		return uint64(n), nil
+	case int:
+		if n < 0 {
+			return 0, fmt.Errorf("tag number must not be negative, got %d", n)
+		}
+		return uint64(n), nil
+	default:
+		return 0, fmt.Errorf("expected a tag number, got %T", v)
+	}
+}
+
+// walkDecoded recursively converts any cbor.Tag values found within a
+// decoded CBOR structure into their JSON-safe sentinel shape.
+//
+// Tags 2 and 3 (bignum / negative bignum) need special handling here:
+// fxamacker/cbor decodes them straight to math/big.Int whenever the
+// target is an `any`, unconditionally and regardless of any DecOptions
+// or TagSet (see its decode.go handling of tagNumUnsignedBignum/
+// tagNumNegativeBignum) — a generic cbor.Tag is never produced for
+// them, so they'd otherwise bypass decodeTagValue/tagHandlers entirely
+// and hit json.Marshal as a bare big.Int, which has a pointer-receiver
+// MarshalJSON that a non-pointer interface value never invokes,
+// silently serializing as `{}`. bignumToTag reconstructs the
+// cbor.Tag{2 or 3, <raw bytes>} a generic decode would have produced
+// for any other tag number, so it can flow through the same path.
+func (r *tagRegistry) walkDecoded(v any) (any, error) {
+	switch val := v.(type) {
+	case cbor.Tag:
+		content, err := r.walkDecoded(val.Content)
+		if err != nil {
+			return nil, err
+		}
+		return r.decodeTagValue(cbor.Tag{Number: val.Number, Content: content})
+	case big.Int:
+		return r.decodeTagValue(bignumToTag(&val))
+	case *big.Int:
+		return r.decodeTagValue(bignumToTag(val))
+	case map[string]any:
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			converted, err := r.walkDecoded(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			converted, err := r.walkDecoded(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
+
+// walkParsed recursively converts any tag sentinels found within a
+// parsed JSON structure into cbor.Tag values ready for CBOR encoding.
+func (r *tagRegistry) walkParsed(v any) (any, error) {
+	switch val := v.(type) {
+	case map[string]any:
+		if tag, ok, err := r.encodeTagValue(val); err != nil {
+			return nil, err
+		} else if ok {
+			content, err := r.walkParsed(tag.Content)
+			if err != nil {
+				return nil, err
+			}
+			tag.Content = content
+			return tag, nil
+		}
+
+		out := make(map[string]any, len(val))
+		for k, item := range val {
+			converted, err := r.walkParsed(item)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = converted
+		}
+		return out, nil
+	case []any:
+		out := make([]any, len(val))
+		for i, item := range val {
+			converted, err := r.walkParsed(item)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = converted
+		}
+		return out, nil
+	default:
+		return v, nil
+	}
+}
@@ -0,0 +1,93 @@
+package cbor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/stretchr/testify/require"
+	"github.com/warpstreamlabs/bento/public/service"
+)
+
+func TestCBORTagsWrappedToJSON(t *testing.T) {
+	encMode, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	cborData, err := encMode.Marshal(cbor.Tag{Number: 30, Content: []any{1, 3}})
+	require.NoError(t, err)
+
+	proc, err := NewProcessor("to_json", WithTags([]TagEntry{
+		{Number: 30, Name: "rational"},
+	}))
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage(cborData))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	jsonBytes, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	var act map[string]any
+	require.NoError(t, json.Unmarshal(jsonBytes, &act))
+	require.Equal(t, float64(30), act[tagKey])
+	require.Equal(t, []any{float64(1), float64(3)}, act[tagValueKey])
+}
+
+func TestCBORTagsBignumRoundTrip(t *testing.T) {
+	encMode, err := cbor.EncOptions{}.EncMode()
+	require.NoError(t, err)
+
+	// A bignum too large for an int64/uint64: 2^64.
+	raw := []byte{0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	cborData, err := encMode.Marshal(cbor.Tag{Number: 2, Content: raw})
+	require.NoError(t, err)
+
+	toJSON, err := NewProcessor("to_json", WithTags([]TagEntry{
+		{Number: 2, Name: "bignum"},
+	}))
+	require.NoError(t, err)
+
+	msgs, err := toJSON.Process(context.Background(), service.NewMessage(cborData))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	jsonBytes, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	fromJSON, err := NewProcessor("from_json", WithTags([]TagEntry{
+		{Number: 2, Name: "bignum"},
+	}))
+	require.NoError(t, err)
+
+	msgs, err = fromJSON.Process(context.Background(), service.NewMessage(jsonBytes))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	roundTripped, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	var tag cbor.Tag
+	require.NoError(t, cbor.Unmarshal(roundTripped, &tag))
+	require.Equal(t, uint64(2), tag.Number)
+	require.Equal(t, raw, tag.Content.([]byte))
+}
+
+func TestCBORTagsNamedFromJSON(t *testing.T) {
+	proc, err := NewProcessor("from_json", WithTags([]TagEntry{
+		{Number: 258, Name: "set", JSONRepresentation: string(representationNamed)},
+	}))
+	require.NoError(t, err)
+
+	msgs, err := proc.Process(context.Background(), service.NewMessage([]byte(`{"set": [1, 2, 3]}`)))
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+
+	cborData, err := msgs[0].AsBytes()
+	require.NoError(t, err)
+
+	var tag cbor.Tag
+	require.NoError(t, cbor.Unmarshal(cborData, &tag))
+	require.Equal(t, uint64(258), tag.Number)
+}